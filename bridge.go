@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	irc "github.com/thoj/go-ircevent"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// bridgeSendTimeout bounds webhookBridge.Send and discordBridge.Send so a hung endpoint can't
+// stall the caller (OnMessage runs off the single listenWrite goroutine but still shouldn't
+// block forever)
+const bridgeSendTimeout = 10 * time.Second
+
+var bridgeHTTPClient = &http.Client{Timeout: bridgeSendTimeout}
+
+// Bridge relays Message values to and from an external chat system
+type Bridge interface {
+	// Name returns the bridge identifier, as configured in commands.yml
+	Name() string
+
+	// Send forwards message to the external system
+	Send(message *Message) error
+
+	// Receive returns the channel on which messages coming from the external system are published
+	Receive() <-chan *Message
+
+	// Close shuts down the bridge and its underlying connection
+	Close() error
+}
+
+// BridgeConfig describes a single entry of the commands.yml bridges: section
+type BridgeConfig struct {
+	Type         string            `yaml:"type"` // discord, irc or webhook
+	AuthorPrefix string            `yaml:"author_prefix"`
+	Options      map[string]string `yaml:"options"`
+}
+
+// NewBridge builds the Bridge matching cfg.Type
+func NewBridge(name string, cfg *BridgeConfig) (Bridge, error) {
+	switch cfg.Type {
+	case "discord":
+		return newDiscordBridge(name, cfg)
+	case "irc":
+		return newIRCBridge(name, cfg)
+	case "webhook":
+		return newWebhookBridge(name, cfg)
+	default:
+		return nil, fmt.Errorf("unknown bridge type %q for bridge %q", cfg.Type, name)
+	}
+}
+
+// Gateway fans messages coming from the Owncast ChatService out to every configured bridge and,
+// conversely, injects messages received from any bridge back into Owncast
+type Gateway struct {
+	chat     *ChatService
+	botName  string
+	bridges  map[string]Bridge
+	prefixes map[string]string // per-bridge author prefix, see BridgeConfig.AuthorPrefix
+}
+
+// NewGateway builds a Gateway relaying chat messages between chat and the given bridges.
+// botName is used to recognize and drop messages the bot itself re-emits on a bridge. configs
+// supplies each bridge's AuthorPrefix, defaulting to "[name] " when unset.
+func NewGateway(chat *ChatService, botName string, bridges map[string]Bridge, configs map[string]*BridgeConfig) *Gateway {
+	prefixes := make(map[string]string, len(bridges))
+	for name := range bridges {
+		prefix := "[" + name + "] "
+		if cfg, ok := configs[name]; ok && cfg.AuthorPrefix != "" {
+			prefix = cfg.AuthorPrefix
+		}
+		prefixes[name] = prefix
+	}
+
+	return &Gateway{
+		chat:     chat,
+		botName:  botName,
+		bridges:  bridges,
+		prefixes: prefixes,
+	}
+}
+
+// Start relaying messages in both directions. It returns immediately, spawning one goroutine per
+// configured bridge.
+func (g *Gateway) Start() {
+	for name, b := range g.bridges {
+		go g.relayFromBridge(name, b)
+	}
+}
+
+// Close shuts down every configured bridge
+func (g *Gateway) Close() error {
+	for name, b := range g.bridges {
+		if err := b.Close(); err != nil {
+			log.WithField("bridge", name).Errorln(err)
+		}
+	}
+	return nil
+}
+
+// BroadcastToBridges forwards a message received on the Owncast chat to every configured bridge,
+// skipping messages that originate from a bridge in the first place to avoid relay loops.
+func (g *Gateway) BroadcastToBridges(message *Message) {
+	if message.Origin != "" {
+		log.Debugf("not re-broadcasting message already relayed from bridge %q", message.Origin)
+		return
+	}
+
+	for name, b := range g.bridges {
+		if err := b.Send(message); err != nil {
+			log.WithField("bridge", name).Errorln(err)
+		}
+	}
+}
+
+// relayFromBridge injects messages coming from b back into Owncast as CHAT messages
+func (g *Gateway) relayFromBridge(name string, b Bridge) {
+	prefix := g.prefixes[name]
+	for message := range b.Receive() {
+		// Loop prevention: never re-emit a message the bot itself sent to the far side
+		if message.Author == g.botName {
+			continue
+		}
+
+		author := message.Author
+		message.Origin = name
+		message.Type = CHAT
+		message.Body = prefix + author + " " + message.Body
+		message.Author = BotName
+
+		if err := g.chat.send(message); err != nil {
+			log.WithField("bridge", name).Errorln(err)
+		}
+	}
+}
+
+// discordBridge relays messages to/from a Discord guild text channel via discordgo
+type discordBridge struct {
+	name      string
+	channelID string
+	session   *discordgo.Session
+	recvCh    chan *Message
+}
+
+func newDiscordBridge(name string, cfg *BridgeConfig) (*discordBridge, error) {
+	token := cfg.Options["token"]
+	channelID := cfg.Options["channel_id"]
+	if token == "" || channelID == "" {
+		return nil, fmt.Errorf("bridge %q: discord requires options.token and options.channel_id", name)
+	}
+
+	session, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, fmt.Errorf("bridge %q: %v", name, err)
+	}
+	session.Client.Timeout = bridgeSendTimeout
+
+	b := &discordBridge{
+		name:      name,
+		channelID: channelID,
+		session:   session,
+		recvCh:    make(chan *Message),
+	}
+
+	session.AddHandler(b.onMessageCreate)
+	if err := session.Open(); err != nil {
+		return nil, fmt.Errorf("bridge %q: %v", name, err)
+	}
+
+	return b, nil
+}
+
+func (b *discordBridge) Name() string { return b.name }
+
+func (b *discordBridge) Send(message *Message) error {
+	_, err := b.session.ChannelMessageSend(b.channelID, fmt.Sprintf("%s: %s", message.Author, message.Body))
+	return err
+}
+
+func (b *discordBridge) Receive() <-chan *Message { return b.recvCh }
+
+func (b *discordBridge) Close() error { return b.session.Close() }
+
+func (b *discordBridge) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.ChannelID != b.channelID || m.Author.Bot {
+		return
+	}
+
+	b.recvCh <- &Message{Author: m.Author.Username, Body: m.Content}
+}
+
+// ircBridge relays messages to/from a single IRC channel via go-ircevent
+type ircBridge struct {
+	name    string
+	channel string
+	conn    *irc.Connection
+	recvCh  chan *Message
+}
+
+func newIRCBridge(name string, cfg *BridgeConfig) (*ircBridge, error) {
+	server := cfg.Options["server"]
+	channel := cfg.Options["channel"]
+	nick := cfg.Options["nick"]
+	if server == "" || channel == "" || nick == "" {
+		return nil, fmt.Errorf("bridge %q: irc requires options.server, options.channel and options.nick", name)
+	}
+
+	conn := irc.IRC(nick, nick)
+	b := &ircBridge{
+		name:    name,
+		channel: channel,
+		conn:    conn,
+		recvCh:  make(chan *Message),
+	}
+
+	conn.AddCallback("001", func(e *irc.Event) { conn.Join(channel) })
+	conn.AddCallback("PRIVMSG", b.onPrivmsg)
+
+	if err := conn.Connect(server); err != nil {
+		return nil, fmt.Errorf("bridge %q: %v", name, err)
+	}
+	go conn.Loop()
+
+	return b, nil
+}
+
+func (b *ircBridge) Name() string { return b.name }
+
+func (b *ircBridge) Send(message *Message) error {
+	b.conn.Privmsg(b.channel, fmt.Sprintf("%s: %s", message.Author, message.Body))
+	return nil
+}
+
+func (b *ircBridge) Receive() <-chan *Message { return b.recvCh }
+
+func (b *ircBridge) Close() error {
+	b.conn.Quit()
+	return nil
+}
+
+func (b *ircBridge) onPrivmsg(e *irc.Event) {
+	if len(e.Arguments) < 2 || e.Arguments[0] != b.channel {
+		return
+	}
+
+	b.recvCh <- &Message{Author: e.Nick, Body: e.Arguments[1]}
+}
+
+// webhookBridge forwards messages as JSON HTTP POST requests and never relays anything back, since
+// generic webhooks are outbound-only
+type webhookBridge struct {
+	name string
+	url  string
+}
+
+func newWebhookBridge(name string, cfg *BridgeConfig) (*webhookBridge, error) {
+	url := cfg.Options["url"]
+	if url == "" {
+		return nil, fmt.Errorf("bridge %q: webhook requires options.url", name)
+	}
+
+	return &webhookBridge{name: name, url: url}, nil
+}
+
+func (b *webhookBridge) Name() string { return b.name }
+
+func (b *webhookBridge) Send(message *Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	resp, err := bridgeHTTPClient.Post(b.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bridge %q: webhook returned status %d", b.name, resp.StatusCode)
+	}
+	return nil
+}
+
+// Receive is a no-op: this generic webhook bridge is outbound-only
+func (b *webhookBridge) Receive() <-chan *Message { return nil }
+
+func (b *webhookBridge) Close() error { return nil }
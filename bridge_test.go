@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestNewBridgeUnknownType(t *testing.T) {
+	_, err := NewBridge("mystery", &BridgeConfig{Type: "carrier-pigeon"})
+	if err == nil {
+		t.Fatal("NewBridge with an unknown type should return an error")
+	}
+}
+
+func TestNewGatewayPrefixes(t *testing.T) {
+	bridges := map[string]Bridge{"discord": nil, "irc": nil}
+	configs := map[string]*BridgeConfig{
+		"discord": {AuthorPrefix: "(discord) "},
+		// irc has no config entry: NewGateway must fall back to the default prefix
+	}
+
+	g := NewGateway(nil, "bot", bridges, configs)
+
+	if got, want := g.prefixes["discord"], "(discord) "; got != want {
+		t.Errorf("prefixes[discord] = %q, want %q", got, want)
+	}
+	if got, want := g.prefixes["irc"], "[irc] "; got != want {
+		t.Errorf("prefixes[irc] = %q, want %q", got, want)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -21,9 +24,25 @@ const (
 	SYSTEM        = "SYSTEM"
 	NAME_CHANGE   = "NAME_CHANGE"
 	requestOrigin = "http://localhost"
+
+	// defaultReconnectMinBackoff is the delay before the very first reconnect attempt.
+	defaultReconnectMinBackoff = time.Second
+
+	// defaultReconnectMaxBackoff is used when Config.ReconnectMaxBackoff is unset.
+	defaultReconnectMaxBackoff = time.Minute
+
+	// defaultPingTimeout is used when Config.PingTimeout is unset.
+	defaultPingTimeout = time.Minute
+
+	// defaultWriteQueueSize is used when Config.WriteQueueSize is unset.
+	defaultWriteQueueSize = 32
+
+	// writeWait is how long a single write is allowed to take before it is considered failed.
+	writeWait = 10 * time.Second
 )
 
 var ErrCloseConnectionTimeout = errors.New("ChatService.Close(): timeout exceeded while closing websocket connection")
+var ErrWriteQueueFull = errors.New("ChatService.send(): write queue is full")
 
 // ProcessMessageFunc get a received message from chat and return the answer.
 // answer can be nil.
@@ -38,6 +57,24 @@ type Message struct {
 	Type      string    `json:"type"`
 	Visible   bool      `json:"visible,omitempty"`
 	Timestamp time.Time `json:"timestamp,omitempty"`
+
+	// Origin names the bridge this message was relayed from, if any. It is never sent to
+	// Owncast; it only exists so a Gateway can tell a relayed message from a local one and avoid
+	// bouncing it back to the bridge it came from.
+	Origin string `json:"-"`
+
+	// User carries the author's role information. Owncast only sends it on SYSTEM join events —
+	// it is absent from plain CHAT messages, so a CHAT message's author is correlated back to a
+	// previously-observed User by display name. See UserRegistry.
+	User *ChatUser `json:"user,omitempty"`
+}
+
+// ChatUser describes the author of a chat message, as sent by Owncast on SYSTEM join events
+type ChatUser struct {
+	ID            string `json:"id,omitempty"`
+	DisplayName   string `json:"displayName,omitempty"`
+	IsModerator   bool   `json:"isModerator,omitempty"`
+	IsBroadcaster bool   `json:"isBroadcaster,omitempty"`
 }
 
 // String simply print message as JSON value
@@ -48,11 +85,26 @@ func (c Message) String() string {
 
 // ChatService performs action on Owncast chat
 type ChatService struct {
-	ws      *websocket.Conn
-	pingCh  chan *Message
-	chatCh  chan *Message
+	config *Config
+
+	mu     sync.RWMutex // guards ws, swapped on every reconnect
+	ws     *websocket.Conn
+	pingCh chan *Message
+	chatCh chan *Message
+	doneCh chan bool
+
+	// writeCh is the only path allowed to touch ws for writes: gorilla/websocket forbids
+	// concurrent writers, so a single writer goroutine owns ws and drains this channel.
+	writeCh chan *writeOp
+
 	jobFunc ProcessMessageFunc
-	doneCh  chan bool
+
+	closing int32 // set to 1 once Close has been called, see isClosing
+
+	pingMu       sync.Mutex
+	lastPing     time.Time
+	pingInterval time.Duration
+	pingSeen     bool // lastPing comes from an actual PING, not from a (re)connect
 }
 
 // Config is required filed to initiate a websocket connection
@@ -61,6 +113,57 @@ type Config struct {
 	Host                string
 	Path                string
 	CommandExecutorFunc ProcessMessageFunc
+
+	// ReconnectMaxBackoff caps the exponential backoff delay applied between reconnect attempts.
+	// Defaults to defaultReconnectMaxBackoff.
+	ReconnectMaxBackoff time.Duration
+
+	// MaxReconnectAttempts limits how many consecutive reconnect attempts are made before giving
+	// up on the connection for good. Zero means retry forever.
+	MaxReconnectAttempts int
+
+	// PingTimeout is the maximum duration allowed without receiving a PING from the server before
+	// the connection is considered dead and a reconnect is triggered. Once a PING cadence has been
+	// observed, twice that interval is used instead, capped to PingTimeout.
+	// Defaults to defaultPingTimeout.
+	PingTimeout time.Duration
+
+	// OnConnect, if set, is called every time a websocket connection is established, including
+	// reconnects.
+	OnConnect func()
+
+	// OnDisconnect, if set, is called whenever the connection is lost, with the error that caused
+	// it. err is nil when the connection was closed on purpose via Close.
+	OnDisconnect func(err error)
+
+	// WriteQueueSize bounds the number of outbound writes buffered for the writer goroutine.
+	// Defaults to defaultWriteQueueSize.
+	WriteQueueSize int
+
+	// WriteBlocking, when true, makes send block once the write queue is full instead of
+	// dropping the message. Defaults to false: drop the message and log a warning.
+	WriteBlocking bool
+
+	// OnMessage, if set, is called with every incoming CHAT message, in addition to
+	// CommandExecutorFunc. Used e.g. by a Gateway to relay chat out to configured bridges.
+	OnMessage func(message *Message)
+
+	// OnSystemMessage, if set, is called with every incoming SYSTEM message, which is otherwise
+	// ignored. Used to feed a UserRegistry from join events carrying role information.
+	OnSystemMessage func(message *Message)
+
+	// Sinks receive every outgoing command answer. Defaults to a single Sink writing back to the
+	// Owncast chat websocket, see NewChatService.
+	Sinks []Sink
+}
+
+// writeOp describes a single write owned exclusively by ChatService's writer goroutine, either an
+// application Message or a control frame (e.g. a close frame).
+type writeOp struct {
+	message     *Message
+	controlType int
+	controlData []byte
+	result      chan error // optional, set when the caller needs to observe the write outcome
 }
 
 func (c *Config) validate() error {
@@ -84,39 +187,89 @@ func (c *Config) validate() error {
 	return nil
 }
 
-// NewChatService create a new websocket listener
-func NewChatService(config *Config) (*ChatService, error) {
-	if err := config.validate(); err != nil {
-		return nil, err
+// setDefaults fills zero-valued reconnect/heartbeat settings with sane defaults
+func (c *Config) setDefaults() {
+	if c.ReconnectMaxBackoff <= 0 {
+		c.ReconnectMaxBackoff = defaultReconnectMaxBackoff
+	}
+
+	if c.PingTimeout <= 0 {
+		c.PingTimeout = defaultPingTimeout
 	}
 
+	if c.WriteQueueSize <= 0 {
+		c.WriteQueueSize = defaultWriteQueueSize
+	}
+}
+
+// dial opens a new websocket connection using the given configuration
+func dial(config *Config) (*websocket.Conn, error) {
 	u := &url.URL{
 		Scheme: config.Scheme,
 		Host:   config.Host,
 		Path:   config.Path,
 	}
+
 	log.Debugf("connecting to %s", u.String())
 	c, _, err := websocket.DefaultDialer.Dial(u.String(), http.Header{"Origin": {requestOrigin}})
+	return c, err
+}
+
+// NewChatService create a new websocket listener
+func NewChatService(config *Config) (*ChatService, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	config.setDefaults()
+
+	c, err := dial(config)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ChatService{
+	service := &ChatService{
+		config:  config,
 		ws:      c,
 		pingCh:  make(chan *Message),
 		chatCh:  make(chan *Message),
 		doneCh:  make(chan bool),
+		writeCh: make(chan *writeOp, config.WriteQueueSize),
 		jobFunc: config.CommandExecutorFunc,
-	}, nil
+	}
+
+	if len(config.Sinks) == 0 {
+		config.Sinks = []Sink{&chatSink{chat: service}}
+	}
+
+	return service, nil
 }
 
 // Close websocket connection
 func (c *ChatService) Close(ctx context.Context) error {
+	atomic.StoreInt32(&c.closing, 1)
+
 	// Cleanly close the connection by sending a close message and then
 	// waiting (with timeout) for the server to close the connection.
-	err := c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-	if err != nil {
-		return fmt.Errorf("ChatService.Close(): %v", err)
+	result := make(chan error, 1)
+	op := &writeOp{
+		controlType: websocket.CloseMessage,
+		controlData: websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		result:      result,
+	}
+
+	select {
+	case c.writeCh <- op:
+	case <-ctx.Done():
+		return ErrCloseConnectionTimeout
+	}
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return fmt.Errorf("ChatService.Close(): %v", err)
+		}
+	case <-ctx.Done():
+		return ErrCloseConnectionTimeout
 	}
 
 	select {
@@ -129,27 +282,228 @@ func (c *ChatService) Close(ctx context.Context) error {
 
 // Listen start routines to listen for input/output messages
 func (c *ChatService) Listen() {
+	c.resetPingDeadline()
+	c.notifyConnect()
 	go c.listenRead()
 	go c.listenWrite()
+	go c.writer()
+	go c.superviseHeartbeat()
+}
+
+func (c *ChatService) isClosing() bool {
+	return atomic.LoadInt32(&c.closing) == 1
+}
+
+func (c *ChatService) currentConn() *websocket.Conn {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ws
+}
+
+func (c *ChatService) notifyConnect() {
+	if c.config.OnConnect != nil {
+		c.config.OnConnect()
+	}
+}
+
+func (c *ChatService) notifyDisconnect(err error) {
+	if c.config.OnDisconnect != nil {
+		c.config.OnDisconnect(err)
+	}
 }
 
+// send posts message to the writer goroutine's queue, never touching ws directly
 func (c *ChatService) send(message *Message) error {
-	return c.ws.WriteJSON(message)
+	return c.enqueue(&writeOp{message: message})
+}
+
+// writeToSinks fans a command answer out to every configured Sink, logging (but not stopping on)
+// individual failures
+func (c *ChatService) writeToSinks(message *Message) {
+	for _, s := range c.config.Sinks {
+		if err := s.Write(context.Background(), message); err != nil {
+			log.WithField("body", message.Body).WithField("type", message.Type).Errorln(err)
+		}
+	}
+}
+
+// enqueue posts op to the write queue, honouring config.WriteBlocking when it is full
+func (c *ChatService) enqueue(op *writeOp) error {
+	if c.config.WriteBlocking {
+		c.writeCh <- op
+		return nil
+	}
+
+	select {
+	case c.writeCh <- op:
+		return nil
+	default:
+		log.Warnf("write queue full (size=%d), dropping message", c.config.WriteQueueSize)
+		return ErrWriteQueueFull
+	}
+}
+
+// writer is the single goroutine allowed to write to ws, serializing application messages and
+// control frames (e.g. Close) posted on writeCh
+func (c *ChatService) writer() {
+	for {
+		select {
+		case <-c.doneCh:
+			return
+
+		case op := <-c.writeCh:
+			err := c.write(op)
+			if op.result != nil {
+				op.result <- err
+			} else if err != nil {
+				log.Errorln(err)
+			}
+		}
+	}
+}
+
+func (c *ChatService) write(op *writeOp) error {
+	ws := c.currentConn()
+	if err := ws.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		return err
+	}
+
+	if op.controlType != 0 {
+		return ws.WriteMessage(op.controlType, op.controlData)
+	}
+	return ws.WriteJSON(op.message)
+}
+
+// nextBackoff doubles backoff, capping the result to max
+func nextBackoff(backoff, max time.Duration) time.Duration {
+	if backoff *= 2; backoff > max {
+		return max
+	}
+	return backoff
+}
+
+// jitteredWait adds up to 50% random jitter on top of backoff, so that multiple clients
+// reconnecting after the same outage don't all retry in lockstep
+func jitteredWait(backoff time.Duration) time.Duration {
+	return backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+}
+
+// reconnect tears down the current connection and re-dials with an exponential backoff (capped at
+// config.ReconnectMaxBackoff, with jitter) until a new connection succeeds or
+// config.MaxReconnectAttempts is exhausted. It returns false when giving up.
+func (c *ChatService) reconnect(cause error) bool {
+	c.notifyDisconnect(cause)
+
+	backoff := defaultReconnectMinBackoff
+	for attempt := 1; c.config.MaxReconnectAttempts == 0 || attempt <= c.config.MaxReconnectAttempts; attempt++ {
+		if c.isClosing() {
+			return false
+		}
+
+		wait := jitteredWait(backoff)
+		log.Warnf("websocket connection lost, reconnecting in %s (attempt %d): %v", wait, attempt, cause)
+		time.Sleep(wait)
+
+		ws, err := dial(c.config)
+		if err != nil {
+			log.Errorf("reconnect attempt %d failed: %v", attempt, err)
+			backoff = nextBackoff(backoff, c.config.ReconnectMaxBackoff)
+			continue
+		}
+
+		c.mu.Lock()
+		c.ws = ws
+		c.mu.Unlock()
+
+		c.resetPingDeadline()
+		c.notifyConnect()
+		return true
+	}
+
+	log.Errorf("giving up reconnecting after %d attempts", c.config.MaxReconnectAttempts)
+	return false
+}
+
+// recordPing tracks the observed PING cadence so superviseHeartbeat can derive a liveness deadline
+func (c *ChatService) recordPing() {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+
+	now := time.Now()
+	if c.pingSeen {
+		c.pingInterval = now.Sub(c.lastPing)
+	}
+	c.lastPing = now
+	c.pingSeen = true
+}
+
+func (c *ChatService) resetPingDeadline() {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+	c.lastPing = time.Now()
+	c.pingSeen = false
+}
+
+// pingDeadline returns how long we tolerate silence from the server before assuming the
+// connection is dead, i.e. 2x the observed PING interval, capped to config.PingTimeout.
+func (c *ChatService) pingDeadline() time.Duration {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+
+	if d := 2 * c.pingInterval; c.pingInterval > 0 && d < c.config.PingTimeout {
+		return d
+	}
+	return c.config.PingTimeout
+}
+
+// superviseHeartbeat proactively closes and reconnects the socket if no PING arrives in time
+func (c *ChatService) superviseHeartbeat() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.doneCh:
+			return
+
+		case <-ticker.C:
+			c.pingMu.Lock()
+			last := c.lastPing
+			c.pingMu.Unlock()
+
+			if last.IsZero() || time.Since(last) < c.pingDeadline() {
+				continue
+			}
+
+			log.Warnln("no PING received within deadline, forcing reconnect")
+			_ = c.currentConn().Close()
+		}
+	}
 }
 
 // listenRead receive each chat messages and dispatch them to related Go channel
 func (c *ChatService) listenRead() {
-	defer close(c.doneCh)
 	for {
 		var message Message
-		if err := c.ws.ReadJSON(&message); err != nil {
+		if err := c.currentConn().ReadJSON(&message); err != nil {
+			if c.isClosing() {
+				c.notifyDisconnect(nil)
+				close(c.doneCh)
+				return
+			}
+
 			// If unexpected error
 			v, ok := err.(*websocket.CloseError)
 			if !ok || v.Code != websocket.CloseNormalClosure {
 				log.WithField("body", message.Body).WithField("type", message.Type).Errorln(err)
 			}
 
-			return
+			if !c.reconnect(err) {
+				close(c.doneCh)
+				return
+			}
+
+			continue
 		}
 
 		log.Debugf("Received %s request", message.Type)
@@ -157,12 +511,19 @@ func (c *ChatService) listenRead() {
 		// Dispatch message
 		switch message.Type {
 		case PING:
+			c.recordPing()
 			c.pingCh <- &message
 		case CHAT:
 			c.chatCh <- &message
 
-		// Just ignore this kind of message
+		// SYSTEM messages carry join events with the author's role, used to build the user
+		// registry consulted for command scopes
 		case SYSTEM:
+			if c.config.OnSystemMessage != nil {
+				c.config.OnSystemMessage(&message)
+			}
+
+		// Just ignore this kind of message
 		case NAME_CHANGE:
 
 		default:
@@ -189,12 +550,17 @@ func (c *ChatService) listenWrite() {
 
 		// Listen incoming to generic message
 		case input := <-c.chatCh:
+			// OnMessage may fan out to bridges, which hit the network (each Send bounded
+			// only by bridgeSendTimeout); never block this goroutine on it, or PONGs and
+			// further dispatch stall for as long as the bridges take.
+			if c.config.OnMessage != nil {
+				go c.config.OnMessage(input)
+			}
+
 			// Separate routine to process multiple messages at once
 			go func(m *Message) {
 				if output := c.jobFunc(m); output != nil {
-					if err := c.send(output); err != nil {
-						log.WithField("body", output.Body).WithField("type", output.Type).Errorln(err)
-					}
+					c.writeToSinks(output)
 				}
 			}(input)
 		}
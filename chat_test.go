@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextBackoff(t *testing.T) {
+	cases := []struct {
+		name    string
+		backoff time.Duration
+		max     time.Duration
+		want    time.Duration
+	}{
+		{"doubles below the cap", time.Second, time.Minute, 2 * time.Second},
+		{"caps at max", 40 * time.Second, time.Minute, time.Minute},
+		{"already at max stays at max", time.Minute, time.Minute, time.Minute},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := nextBackoff(tc.backoff, tc.max); got != tc.want {
+				t.Errorf("nextBackoff(%s, %s) = %s, want %s", tc.backoff, tc.max, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJitteredWait(t *testing.T) {
+	backoff := 10 * time.Second
+
+	for i := 0; i < 50; i++ {
+		wait := jitteredWait(backoff)
+		if wait < backoff || wait > backoff+backoff/2 {
+			t.Fatalf("jitteredWait(%s) = %s, want a value in [%s, %s]", backoff, wait, backoff, backoff+backoff/2)
+		}
+	}
+}
+
+func TestPingDeadline(t *testing.T) {
+	cases := []struct {
+		name         string
+		pingInterval time.Duration
+		pingTimeout  time.Duration
+		want         time.Duration
+	}{
+		{"no observed interval yet falls back to PingTimeout", 0, time.Minute, time.Minute},
+		{"twice the observed interval, below PingTimeout", 10 * time.Second, time.Minute, 20 * time.Second},
+		{"observed interval capped to PingTimeout", time.Minute, time.Minute, time.Minute},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &ChatService{
+				config:       &Config{PingTimeout: tc.pingTimeout},
+				pingInterval: tc.pingInterval,
+			}
+
+			if got := c.pingDeadline(); got != tc.want {
+				t.Errorf("pingDeadline() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
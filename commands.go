@@ -5,17 +5,70 @@ import (
 	"html/template"
 	"io/ioutil"
 	"log"
+	"time"
 
 	"github.com/kyokomi/emoji"
 	"gopkg.in/yaml.v2"
 )
 
+// defaultDenyTemplate is used to answer a command whose Scope forbids the author from running it,
+// unless the command sets its own DenyTemplate
+const defaultDenyTemplate = "@{{.Author}} you don't have permission to run this command."
+
 type commandFile struct {
-	File []*Command `yaml:"commands"`
+	File    []*Command               `yaml:"commands"`
+	Bridges map[string]*BridgeConfig `yaml:"bridges"`
+	Sinks   []*SinkConfig            `yaml:"sinks"`
 }
 
-// ReadCommandsFromFile read and parse the given YAML file
+// ReadCommandsFromFile read and parse the given YAML file, loading each command's plugin, if any,
+// unless plugins are disabled (see the --no-plugins flag)
 func ReadCommandsFromFile(file string) ([]*Command, error) {
+	f, err := readCommandFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pluginsEnabled {
+		return f.File, nil
+	}
+
+	for _, c := range f.File {
+		if c.Plugin == "" {
+			continue
+		}
+
+		handler, err := loadPlugin(c.Plugin)
+		if err != nil {
+			return nil, err
+		}
+		c.handler = handler
+	}
+
+	return f.File, nil
+}
+
+// ReadBridgesFromFile read and parse the bridges: section of the given YAML file
+func ReadBridgesFromFile(file string) (map[string]*BridgeConfig, error) {
+	f, err := readCommandFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Bridges, nil
+}
+
+// ReadSinksFromFile read and parse the sinks: section of the given YAML file
+func ReadSinksFromFile(file string) ([]*SinkConfig, error) {
+	f, err := readCommandFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Sinks, nil
+}
+
+func readCommandFile(file string) (*commandFile, error) {
 	data, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, err
@@ -26,27 +79,88 @@ func ReadCommandsFromFile(file string) ([]*Command, error) {
 		log.Fatalf("error: %v", err)
 	}
 
-	return f.File, nil
+	return &f, nil
 }
 
 // Command describe a Owncast stream command
 type Command struct {
 	Trigger  string `yaml:"trigger"`
 	Template string `yaml:"template"`
+
+	// Plugin, if set, points to a Go plugin (.so) whose exported Handler is invoked instead of
+	// rendering Template. See loadPlugin.
+	Plugin string `yaml:"plugin,omitempty"`
+
+	// Aliases are extra triggers that run this same command
+	Aliases []string `yaml:"aliases,omitempty"`
+
+	// Cooldown is the minimum duration between two runs of this command by the same author.
+	// Zero disables cooldown tracking.
+	Cooldown time.Duration `yaml:"cooldown,omitempty"`
+
+	// Scope restricts who may run this command, e.g. [mod, broadcaster]. Empty means anyone.
+	Scope []string `yaml:"scope,omitempty"`
+
+	// DenyTemplate overrides defaultDenyTemplate for this command's denial message
+	DenyTemplate string `yaml:"deny_template,omitempty"`
+
+	// handler is the Plugin's Handler symbol, resolved once by ReadCommandsFromFile
+	handler ProcessMessageFunc
+}
+
+// MatchesTrigger reports whether trigger is this command's Trigger or one of its Aliases
+func (c *Command) MatchesTrigger(trigger string) bool {
+	if c.Trigger == trigger {
+		return true
+	}
+
+	for _, alias := range c.Aliases {
+		if alias == trigger {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commandContext is the data made available to a Command's Template
+type commandContext struct {
+	Args []string
 }
 
 // Parse the current command template and replace placeholders with their respective result
-func (c *Command) Parse() (string, error) {
-	tpl := template.Must(template.New("").Funcs(template.FuncMap{
-		"uptime": Uptime,
-	}).Parse(c.Template))
+func (c *Command) Parse(args []string) (string, error) {
+	funcs := currentPluginFuncs()
+	funcs["uptime"] = Uptime
+
+	tpl := template.Must(template.New("").Funcs(funcs).Parse(c.Template))
 
 	// Execute template functions
 	var content bytes.Buffer
-	if err := tpl.Execute(&content, nil); err != nil {
+	if err := tpl.Execute(&content, commandContext{Args: args}); err != nil {
 		return "", err
 	}
 
 	// Set emojis
 	return emoji.Sprint(content.String()), nil
 }
+
+// Deny renders the message sent back when Scope forbids the author from running this command
+func (c *Command) Deny(input *Message) (string, error) {
+	denyTemplate := c.DenyTemplate
+	if denyTemplate == "" {
+		denyTemplate = defaultDenyTemplate
+	}
+
+	tpl, err := template.New("").Parse(denyTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var content bytes.Buffer
+	if err := tpl.Execute(&content, input); err != nil {
+		return "", err
+	}
+
+	return content.String(), nil
+}
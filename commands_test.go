@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestCommandMatchesTrigger(t *testing.T) {
+	c := &Command{Trigger: "!weather", Aliases: []string{"!meteo", "!w"}}
+
+	for _, trigger := range []string{"!weather", "!meteo", "!w"} {
+		if !c.MatchesTrigger(trigger) {
+			t.Errorf("MatchesTrigger(%q) = false, want true", trigger)
+		}
+	}
+
+	if c.MatchesTrigger("!uptime") {
+		t.Error("MatchesTrigger(\"!uptime\") = true, want false")
+	}
+}
+
+func TestCommandParseWithArgs(t *testing.T) {
+	c := &Command{Template: "{{index .Args 0}} / {{index .Args 1}}"}
+
+	got, err := c.Parse([]string{"Paris", "3d"})
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if want := "Paris / 3d"; got != want {
+		t.Errorf("Parse() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// cooldownSweepInterval is how often stale cooldownTracker entries are dropped
+const cooldownSweepInterval = time.Minute
+
+// cooldownMaxAge is how long a (command, userID) entry is kept after its last use
+const cooldownMaxAge = time.Hour
+
+// cooldownTracker enforces Command.Cooldown per (trigger, userID) pair
+type cooldownTracker struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// newCooldownTracker creates a tracker and starts its periodic sweep goroutine
+func newCooldownTracker() *cooldownTracker {
+	t := &cooldownTracker{last: make(map[string]time.Time)}
+	go t.sweep()
+	return t
+}
+
+func (t *cooldownTracker) key(trigger, userID string) string {
+	return trigger + "|" + userID
+}
+
+// Allow reports whether trigger may run for userID right now. If it may, the attempt is recorded
+// so the next call is correctly rate-limited.
+func (t *cooldownTracker) Allow(trigger, userID string, cooldown time.Duration) bool {
+	if cooldown <= 0 {
+		return true
+	}
+
+	key := t.key(trigger, userID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.last[key]; ok && time.Since(last) < cooldown {
+		return false
+	}
+
+	t.last[key] = time.Now()
+	return true
+}
+
+// sweep periodically drops entries that have not been hit in a while, so the map doesn't grow
+// forever as new users come and go
+func (t *cooldownTracker) sweep() {
+	ticker := time.NewTicker(cooldownSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.mu.Lock()
+		for key, last := range t.last {
+			if time.Since(last) > cooldownMaxAge {
+				delete(t.last, key)
+			}
+		}
+		t.mu.Unlock()
+	}
+}
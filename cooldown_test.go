@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCooldownTrackerAllow(t *testing.T) {
+	tracker := &cooldownTracker{last: make(map[string]time.Time)}
+
+	if !tracker.Allow("!weather", "alice", time.Minute) {
+		t.Fatal("first call should be allowed")
+	}
+
+	if tracker.Allow("!weather", "alice", time.Minute) {
+		t.Fatal("second call within the cooldown window should be blocked")
+	}
+
+	if !tracker.Allow("!weather", "bob", time.Minute) {
+		t.Fatal("a different author should not share alice's cooldown")
+	}
+
+	if !tracker.Allow("!uptime", "alice", time.Minute) {
+		t.Fatal("a different command should not share !weather's cooldown")
+	}
+
+	if !tracker.Allow("!weather", "alice", 0) {
+		t.Fatal("zero cooldown should always be allowed")
+	}
+}
+
+func TestCooldownTrackerAllowAfterExpiry(t *testing.T) {
+	tracker := &cooldownTracker{last: make(map[string]time.Time)}
+	tracker.last[tracker.key("!weather", "alice")] = time.Now().Add(-2 * time.Minute)
+
+	if !tracker.Allow("!weather", "alice", time.Minute) {
+		t.Fatal("call should be allowed once the cooldown window has elapsed")
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"html"
 	"os"
 	"os/signal"
 	"regexp"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -21,10 +24,28 @@ const (
 )
 
 var (
-	commandRegexp = regexp.MustCompile(`^<p>(?P<command>![a-z]+)<\/p>$`)
-	commands      []*Command
+	// commandLineRegexp extracts the raw text of a chat message wrapped in Owncast's <p> tags,
+	// e.g. "<p>!weather Paris 3d</p>" -> "!weather Paris 3d"
+	commandLineRegexp = regexp.MustCompile(`^<p>(.+)<\/p>$`)
+
+	commandStore atomic.Pointer[[]*Command]
+
+	// pluginsEnabled gates Command.Plugin loading, see the --no-plugins flag
+	pluginsEnabled = true
+
+	userRegistry = NewUserRegistry()
+	cooldowns    = newCooldownTracker()
 )
 
+// currentCommands returns the command set currently in effect, possibly hot-swapped by
+// watchCommands
+func currentCommands() []*Command {
+	if cmds := commandStore.Load(); cmds != nil {
+		return *cmds
+	}
+	return nil
+}
+
 func init() {
 	log.SetLevel(log.DebugLevel)
 	log.SetFormatter(&log.TextFormatter{
@@ -39,27 +60,69 @@ func init() {
 
 func main() {
 	var commandFileName string
+	var noPlugins bool
 	flag.StringVar(&commandFileName, "commands-file", "commands.yml", "Describe your commands in this file")
+	flag.BoolVar(&noPlugins, "no-plugins", false, "Disable loading command plugins (required on platforms without Go plugin support, e.g. Windows, or static builds)")
 	flag.Parse()
+	pluginsEnabled = !noPlugins
 
 	// Read commands
-	var err error
-	commands, err = ReadCommandsFromFile(commandFileName)
+	cmds, err := ReadCommandsFromFile(commandFileName)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	commandStore.Store(&cmds)
+
+	if err := watchCommands(commandFileName, &commandStore); err != nil {
+		log.Fatalln(err)
+	}
+
+	// Read and start the configured bridges, if any
+	bridgeConfigs, err := ReadBridgesFromFile(commandFileName)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
+	bridges := make(map[string]Bridge, len(bridgeConfigs))
+	for name, cfg := range bridgeConfigs {
+		b, err := NewBridge(name, cfg)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		bridges[name] = b
+	}
+
 	// Create our chat service with the command parser function
 	chatService, err := NewChatService(&Config{
 		Scheme:              "wss",
 		Host:                "stream.skynewz.dev",
 		Path:                "/entry",
 		CommandExecutorFunc: processMessageCommand,
+		OnSystemMessage:     func(message *Message) { userRegistry.Observe(message.User) },
 	})
 	if err != nil {
 		log.Fatalln(err)
 	}
 
+	gateway := NewGateway(chatService, BotName, bridges, bridgeConfigs)
+	chatService.config.OnMessage = gateway.BroadcastToBridges
+	gateway.Start()
+
+	// Read and wire the configured sinks, if any; NewChatService already defaulted to the chat
+	// websocket sink, so only override it when sinks: was actually set.
+	sinkConfigs, err := ReadSinksFromFile(commandFileName)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if len(sinkConfigs) > 0 {
+		sinks := make([]Sink, len(sinkConfigs))
+		for i, cfg := range sinkConfigs {
+			sinks[i] = NewSink(chatService, cfg)
+		}
+		chatService.config.Sinks = sinks
+	}
+
 	// Start listening to chat messages
 	chatService.Listen()
 
@@ -73,42 +136,73 @@ func main() {
 	if err := chatService.Close(ctx); err != nil {
 		log.Fatalln(err)
 	}
+
+	if err := gateway.Close(); err != nil {
+		log.Fatalln(err)
+	}
 }
 
 // processMessageCommand check if given message is a command and perform associated action
 func processMessageCommand(input *Message) *Message {
 	log.WithField("id", input.ID).Tracef("Received message %s", input.Body)
 
-	// Ensure this is a command message
-	if !commandRegexp.MatchString(input.Body) {
-		log.Tracef("message %q is not a command: %q", input.ID, input.Body)
-		return nil
-	}
-
-	// Get command
-	res := parseGroupsRegexp(commandRegexp, input.Body)
-	v, ok := res["command"]
+	// Ensure this is a command message, and extract its trigger and arguments
+	trigger, args, ok := parseCommandLine(input.Body)
 	if !ok {
-		log.Errorf("command not found in message %q. It should do", input.Body)
+		log.Tracef("message %q is not a command: %q", input.ID, input.Body)
 		return nil
 	}
 
 	// Is it a existent command ?
 	var command *Command
-	for _, c := range commands {
-		if c.Trigger == v {
+	for _, c := range currentCommands() {
+		if c.MatchesTrigger(trigger) {
 			command = c
 			break
 		}
 	}
 
 	if command == nil {
-		log.Tracef("%q: command not found", v)
+		log.Tracef("%q: command not found", trigger)
+		return nil
+	}
+
+	// CHAT messages don't carry User (see Message.User's doc comment), so the author is
+	// correlated back to a previously-observed SYSTEM join event by display name.
+	var userID string
+	if input.User != nil {
+		userID = input.User.ID
+	}
+
+	if !userRegistry.Allowed(userID, input.Author, command.Scope) {
+		log.Debugf("%q: %s lacks the required scope %v", trigger, input.Author, command.Scope)
+		denial, err := command.Deny(input)
+		if err != nil {
+			log.Errorln(err)
+			return nil
+		}
+		return &Message{Author: BotName, Body: denial, Type: CHAT}
+	}
+
+	// Throttle per author: fall back to the display name since CHAT messages rarely carry a
+	// stable User.ID.
+	cooldownKey := userID
+	if cooldownKey == "" {
+		cooldownKey = input.Author
+	}
+
+	if !cooldowns.Allow(command.Trigger, cooldownKey, command.Cooldown) {
+		log.Tracef("%q: %s is on cooldown", trigger, input.Author)
 		return nil
 	}
 
-	log.Debugf("running command %q", v)
-	r, err := command.Parse()
+	log.Debugf("running command %q", trigger)
+
+	if command.handler != nil {
+		return command.handler(input)
+	}
+
+	r, err := command.Parse(args)
 	if err != nil {
 		log.Errorln(err)
 		return nil
@@ -122,15 +216,18 @@ func processMessageCommand(input *Message) *Message {
 	}
 }
 
-// parseGroupsRegexp return a map contains group keys and values from the given pattern
-// https://stackoverflow.com/a/39635221
-func parseGroupsRegexp(re *regexp.Regexp, v string) (r map[string]string) {
-	match := re.FindStringSubmatch(v)
-	r = make(map[string]string)
-	for i, name := range re.SubexpNames() {
-		if i > 0 && i <= len(match) {
-			r[name] = match[i]
-		}
+// parseCommandLine extracts the trigger and argv-style arguments from an Owncast chat message
+// body, e.g. "<p>!weather Paris 3d</p>" -> ("!weather", []string{"Paris", "3d"}, true).
+func parseCommandLine(body string) (trigger string, args []string, ok bool) {
+	matches := commandLineRegexp.FindStringSubmatch(body)
+	if matches == nil {
+		return "", nil, false
 	}
-	return
+
+	fields := strings.Fields(html.UnescapeString(matches[1]))
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "!") {
+		return "", nil, false
+	}
+
+	return fields[0], fields[1:], true
 }
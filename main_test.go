@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	cases := []struct {
+		name        string
+		body        string
+		wantTrigger string
+		wantArgs    []string
+		wantOK      bool
+	}{
+		{"simple command", "<p>!uptime</p>", "!uptime", []string{}, true},
+		{"command with args", "<p>!weather Paris 3d</p>", "!weather", []string{"Paris", "3d"}, true},
+		{"not a command", "<p>hello there</p>", "", nil, false},
+		{"not wrapped in a paragraph", "!uptime", "", nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			trigger, args, ok := parseCommandLine(tc.body)
+			if ok != tc.wantOK {
+				t.Fatalf("parseCommandLine(%q) ok = %v, want %v", tc.body, ok, tc.wantOK)
+			}
+			if !ok {
+				return
+			}
+
+			if trigger != tc.wantTrigger {
+				t.Errorf("parseCommandLine(%q) trigger = %q, want %q", tc.body, trigger, tc.wantTrigger)
+			}
+			if !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("parseCommandLine(%q) args = %v, want %v", tc.body, args, tc.wantArgs)
+			}
+		})
+	}
+}
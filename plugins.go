@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// pluginHandlerSymbol is the exported function every command plugin must define:
+	//   func Handler(*Message) *Message
+	pluginHandlerSymbol = "Handler"
+
+	// pluginTemplateFuncsSymbol is the optional exported symbol a plugin may define to contribute
+	// template functions usable from any command's Template:
+	//   var TemplateFuncs template.FuncMap
+	pluginTemplateFuncsSymbol = "TemplateFuncs"
+)
+
+var (
+	pluginFuncsMu sync.RWMutex
+	pluginFuncs   = template.FuncMap{}
+)
+
+// pluginCacheDir holds content-addressed copies of loaded .so files. Go's plugin.Open caches by
+// path and hands back the original *Plugin forever once a path has been opened, so reloading the
+// same path after the .so has been rewritten in place (e.g. recompiled) silently keeps the stale
+// Handler. Routing loadPlugin through a hash-named copy here means a rewritten .so gets a new
+// path and is actually re-read, while an unchanged one keeps resolving to the same cached copy.
+var pluginCacheDir = filepath.Join(os.TempDir(), "owncast-commands-plugins")
+
+// pluginCachePath copies the .so at path into pluginCacheDir under a name derived from its
+// content hash and returns that copy's path, writing it only if not already cached.
+func pluginCachePath(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading plugin %q: %v", path, err)
+	}
+
+	sum := sha256.Sum256(data)
+	cached := filepath.Join(pluginCacheDir, fmt.Sprintf("%x%s", sum, filepath.Ext(path)))
+
+	if _, err := os.Stat(cached); err == nil {
+		return cached, nil
+	}
+
+	if err := os.MkdirAll(pluginCacheDir, 0o755); err != nil {
+		return "", fmt.Errorf("preparing plugin cache: %v", err)
+	}
+	if err := os.WriteFile(cached, data, 0o644); err != nil {
+		return "", fmt.Errorf("caching plugin %q: %v", path, err)
+	}
+
+	return cached, nil
+}
+
+// loadPlugin opens the .so at path (via its content-addressed cache copy, see pluginCachePath)
+// and resolves its Handler symbol, registering any TemplateFuncs it exports along the way
+func loadPlugin(path string) (ProcessMessageFunc, error) {
+	cached, err := pluginCachePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := plugin.Open(cached)
+	if err != nil {
+		return nil, fmt.Errorf("loading plugin %q: %v", path, err)
+	}
+
+	sym, err := p.Lookup(pluginHandlerSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %q: missing %s symbol: %v", path, pluginHandlerSymbol, err)
+	}
+
+	handler, ok := sym.(func(*Message) *Message)
+	if !ok {
+		return nil, fmt.Errorf("plugin %q: %s has an unexpected signature", path, pluginHandlerSymbol)
+	}
+
+	if funcsSym, err := p.Lookup(pluginTemplateFuncsSymbol); err == nil {
+		funcs, ok := funcsSym.(*template.FuncMap)
+		if !ok {
+			log.Warnf("plugin %q: %s has an unexpected type, ignoring", path, pluginTemplateFuncsSymbol)
+		} else {
+			registerTemplateFuncs(*funcs)
+		}
+	}
+
+	return handler, nil
+}
+
+// registerTemplateFuncs merges funcs into the template functions made available to every
+// Command.Parse call
+func registerTemplateFuncs(funcs template.FuncMap) {
+	pluginFuncsMu.Lock()
+	defer pluginFuncsMu.Unlock()
+
+	for name, fn := range funcs {
+		pluginFuncs[name] = fn
+	}
+}
+
+// currentPluginFuncs returns a snapshot of the template functions contributed by plugins so far
+func currentPluginFuncs() template.FuncMap {
+	pluginFuncsMu.RLock()
+	defer pluginFuncsMu.RUnlock()
+
+	funcs := make(template.FuncMap, len(pluginFuncs))
+	for name, fn := range pluginFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// watchCommands watches commandFileName, and any .so file referenced by a loaded command's
+// Plugin field, reloading the whole command set and atomically swapping store whenever one of
+// them changes. The websocket connection is left untouched.
+func watchCommands(commandFileName string, store *atomic.Pointer[[]*Command]) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watchCommands: %v", err)
+	}
+
+	if err := watcher.Add(commandFileName); err != nil {
+		return fmt.Errorf("watchCommands: %v", err)
+	}
+
+	// Seed the watcher with the .so files already loaded by the initial ReadCommandsFromFile call
+	// in main(), so editing one in place triggers a reload even before commandFileName changes.
+	watchedPlugins := make(map[string]bool)
+	if cmds := store.Load(); cmds != nil {
+		for _, c := range *cmds {
+			if c.Plugin == "" || watchedPlugins[c.Plugin] {
+				continue
+			}
+			if err := watcher.Add(c.Plugin); err != nil {
+				log.WithField("plugin", c.Plugin).Errorln(err)
+				continue
+			}
+			watchedPlugins[c.Plugin] = true
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+
+				log.Debugf("%s changed, reloading commands", event.Name)
+				cmds, err := ReadCommandsFromFile(commandFileName)
+				if err != nil {
+					log.Errorln(err)
+					continue
+				}
+
+				for _, c := range cmds {
+					if c.Plugin == "" || watchedPlugins[c.Plugin] {
+						continue
+					}
+					if err := watcher.Add(c.Plugin); err != nil {
+						log.WithField("plugin", c.Plugin).Errorln(err)
+						continue
+					}
+					watchedPlugins[c.Plugin] = true
+				}
+
+				store.Store(&cmds)
+				log.Infof("reloaded %d commands", len(cmds))
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Errorln(err)
+			}
+		}
+	}()
+
+	log.Debugf("watching %s for changes", filepath.Clean(commandFileName))
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPluginCachePath(t *testing.T) {
+	dir := t.TempDir()
+	pluginCacheDir = filepath.Join(dir, "cache")
+
+	src := filepath.Join(dir, "handler.so")
+	if err := os.WriteFile(src, []byte("v1"), 0o644); err != nil {
+		t.Fatalf("writing fixture plugin: %v", err)
+	}
+
+	first, err := pluginCachePath(src)
+	if err != nil {
+		t.Fatalf("pluginCachePath: %v", err)
+	}
+
+	again, err := pluginCachePath(src)
+	if err != nil {
+		t.Fatalf("pluginCachePath: %v", err)
+	}
+	if again != first {
+		t.Errorf("pluginCachePath for unchanged content = %q, want %q (same cache entry)", again, first)
+	}
+
+	if err := os.WriteFile(src, []byte("v2"), 0o644); err != nil {
+		t.Fatalf("rewriting fixture plugin: %v", err)
+	}
+
+	changed, err := pluginCachePath(src)
+	if err != nil {
+		t.Fatalf("pluginCachePath: %v", err)
+	}
+	if changed == first {
+		t.Error("pluginCachePath returned the same path after the plugin's content changed")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Sink receives every outgoing command answer. Answers reach Owncast chat only through a chat sink
+type Sink interface {
+	Write(ctx context.Context, message *Message) error
+	Close() error
+}
+
+// SinkConfig describes a single entry of the commands.yml sinks: section
+type SinkConfig struct {
+	Type    string            `yaml:"type"` // chat, file, console or webhook
+	Options map[string]string `yaml:"options"`
+}
+
+// NewSink builds the Sink matching cfg.Type. An unknown type falls back to a stdout console sink
+// and logs a warning rather than failing startup.
+func NewSink(chat *ChatService, cfg *SinkConfig) Sink {
+	switch cfg.Type {
+	case "chat":
+		return &chatSink{chat: chat}
+	case "file":
+		return newFileSink(cfg)
+	case "console":
+		return newConsoleSink(cfg)
+	case "webhook":
+		return newWebhookSink(cfg)
+	default:
+		log.Warnf("unknown sink type %q, falling back to console", cfg.Type)
+		return newConsoleSink(cfg)
+	}
+}
+
+// chatSink is the default sink: it writes the message back to the Owncast chat websocket
+type chatSink struct {
+	chat *ChatService
+}
+
+func (s *chatSink) Write(_ context.Context, message *Message) error {
+	return s.chat.send(message)
+}
+
+func (s *chatSink) Close() error { return nil }
+
+// fileSink appends each message as a JSON line to a rotated log file
+type fileSink struct {
+	logger *lumberjack.Logger
+}
+
+func newFileSink(cfg *SinkConfig) *fileSink {
+	path := cfg.Options["path"]
+	if path == "" {
+		path = "commands.log"
+	}
+
+	return &fileSink{logger: &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    atoiOr(cfg.Options["max_size_mb"], 100),
+		MaxAge:     atoiOr(cfg.Options["max_age_days"], 28),
+		MaxBackups: atoiOr(cfg.Options["max_backups"], 3),
+		Compress:   cfg.Options["compress"] == "true",
+	}}
+}
+
+func (s *fileSink) Write(_ context.Context, message *Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.logger.Write(append(data, '\n'))
+	return err
+}
+
+func (s *fileSink) Close() error { return s.logger.Close() }
+
+// consoleSink writes each message to stdout or stderr
+type consoleSink struct {
+	out *os.File
+}
+
+func newConsoleSink(cfg *SinkConfig) *consoleSink {
+	if cfg.Options["stream"] == "stderr" {
+		return &consoleSink{out: os.Stderr}
+	}
+	return &consoleSink{out: os.Stdout}
+}
+
+func (s *consoleSink) Write(_ context.Context, message *Message) error {
+	_, err := fmt.Fprintln(s.out, message.String())
+	return err
+}
+
+func (s *consoleSink) Close() error { return nil }
+
+// webhookSink POSTs each message as JSON to a configured URL, retrying transient failures
+type webhookSink struct {
+	url        string
+	client     *http.Client
+	maxRetries int
+}
+
+func newWebhookSink(cfg *SinkConfig) *webhookSink {
+	return &webhookSink{
+		url:        cfg.Options["url"],
+		client:     &http.Client{Timeout: 10 * time.Second},
+		maxRetries: atoiOr(cfg.Options["max_retries"], 3),
+	}
+}
+
+func (s *webhookSink) Write(ctx context.Context, message *Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook sink: %s returned status %d", s.url, resp.StatusCode)
+	}
+
+	return lastErr
+}
+
+func (s *webhookSink) Close() error { return nil }
+
+func atoiOr(v string, fallback int) int {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestAtoiOr(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    string
+		fallback int
+		want     int
+	}{
+		{"valid number", "42", 7, 42},
+		{"empty value falls back", "", 7, 7},
+		{"garbage value falls back", "not-a-number", 7, 7},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := atoiOr(tc.value, tc.fallback); got != tc.want {
+				t.Errorf("atoiOr(%q, %d) = %d, want %d", tc.value, tc.fallback, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewSinkUnknownTypeFallsBackToConsole(t *testing.T) {
+	sink := NewSink(nil, &SinkConfig{Type: "not-a-real-sink"})
+
+	if _, ok := sink.(*consoleSink); !ok {
+		t.Fatalf("NewSink with an unknown type = %T, want *consoleSink", sink)
+	}
+}
+
+func TestNewSinkKnownTypes(t *testing.T) {
+	for _, sinkType := range []string{"file", "console", "webhook"} {
+		t.Run(sinkType, func(t *testing.T) {
+			sink := NewSink(nil, &SinkConfig{Type: sinkType, Options: map[string]string{"url": "http://example.invalid"}})
+			if sink == nil {
+				t.Fatalf("NewSink(%q) returned nil", sinkType)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import "sync"
+
+// Scopes a Command.Scope entry can require
+const (
+	// ScopeAnon matches any author, including ones never seen in a SYSTEM join event
+	ScopeAnon = "anon"
+
+	ScopeMod         = "mod"
+	ScopeBroadcaster = "broadcaster"
+)
+
+// UserRegistry tracks the role of every chat participant seen so far. Owncast only includes role
+// information (ChatUser) on SYSTEM join events, never on the CHAT messages that actually trigger
+// commands, so lookups are also indexed by display name: a CHAT message's Author matches the
+// DisplayName of the SYSTEM event that announced that same user joining.
+type UserRegistry struct {
+	mu          sync.RWMutex
+	usersByID   map[string]*ChatUser
+	usersByName map[string]*ChatUser
+}
+
+// NewUserRegistry creates an empty UserRegistry
+func NewUserRegistry() *UserRegistry {
+	return &UserRegistry{
+		usersByID:   make(map[string]*ChatUser),
+		usersByName: make(map[string]*ChatUser),
+	}
+}
+
+// Observe records or updates the role of user, indexed by both ID and display name
+func (r *UserRegistry) Observe(user *ChatUser) {
+	if user == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.ID != "" {
+		r.usersByID[user.ID] = user
+	}
+	if user.DisplayName != "" {
+		r.usersByName[user.DisplayName] = user
+	}
+}
+
+func (r *UserRegistry) lookup(userID, displayName string) (*ChatUser, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if userID != "" {
+		if u, ok := r.usersByID[userID]; ok {
+			return u, true
+		}
+	}
+	if displayName != "" {
+		if u, ok := r.usersByName[displayName]; ok {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// scopes returns the scopes the user identified by userID and/or displayName currently qualifies
+// for. Either identifier may be empty; at least one should be set.
+func (r *UserRegistry) scopes(userID, displayName string) []string {
+	u, ok := r.lookup(userID, displayName)
+	if !ok {
+		return nil
+	}
+
+	var scopes []string
+	if u.IsBroadcaster {
+		scopes = append(scopes, ScopeBroadcaster)
+	}
+	if u.IsModerator {
+		scopes = append(scopes, ScopeMod)
+	}
+	return scopes
+}
+
+// Allowed reports whether the user identified by userID and/or displayName satisfies at least one
+// of the required scopes. An empty required list means the command is open to anyone.
+func (r *UserRegistry) Allowed(userID, displayName string, required []string) bool {
+	if len(required) == 0 {
+		return true
+	}
+
+	scopes := r.scopes(userID, displayName)
+	for _, req := range required {
+		if req == ScopeAnon {
+			return true
+		}
+
+		for _, s := range scopes {
+			if s == req {
+				return true
+			}
+		}
+	}
+
+	return false
+}
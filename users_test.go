@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestUserRegistryAllowed(t *testing.T) {
+	r := NewUserRegistry()
+	r.Observe(&ChatUser{ID: "u1", DisplayName: "alice", IsModerator: true})
+	r.Observe(&ChatUser{ID: "u2", DisplayName: "bob"})
+
+	cases := []struct {
+		name        string
+		userID      string
+		displayName string
+		required    []string
+		want        bool
+	}{
+		{"no scope required", "u2", "bob", nil, true},
+		{"anon scope always allowed", "", "stranger", []string{ScopeAnon}, true},
+		{"moderator matches by id", "u1", "", []string{ScopeMod}, true},
+		{"moderator matches by display name when id is unknown", "", "alice", []string{ScopeMod}, true},
+		{"non-moderator denied", "u2", "bob", []string{ScopeMod}, false},
+		{"never-observed author denied", "", "carol", []string{ScopeMod}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := r.Allowed(tc.userID, tc.displayName, tc.required); got != tc.want {
+				t.Errorf("Allowed(%q, %q, %v) = %v, want %v", tc.userID, tc.displayName, tc.required, got, tc.want)
+			}
+		})
+	}
+}